@@ -0,0 +1,56 @@
+package upyun
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Copy duplicates an object already in UPYUN File System to cfg.Path
+// without downloading and re-uploading it. It issues an empty-body PUT
+// carrying X-Upyun-Copy-Source, which UPYUN resolves server-side.
+func (u *UpYun) Copy(ctx context.Context, cfg *CopyObjectConfig) (http.Header, error) {
+	headers := cloneHeaders(cfg.Headers)
+	headers["X-Upyun-Copy-Source"] = sourcePath(u.Bucket, cfg.SrcBucket, cfg.SrcPath)
+
+	_, rtHeaders, err := u.doRESTRequest(ctx, "PUT", cfg.Path, "", headers, nil)
+
+	return rtHeaders, err
+}
+
+// Move renames (moves) an object already in UPYUN File System to cfg.Path
+// without downloading and re-uploading it. It issues an empty-body PUT
+// carrying X-Upyun-Move-Source, which UPYUN resolves server-side and
+// removes the source object once the move succeeds.
+func (u *UpYun) Move(ctx context.Context, cfg *MoveObjectConfig) (http.Header, error) {
+	headers := cloneHeaders(cfg.Headers)
+	headers["X-Upyun-Move-Source"] = sourcePath(u.Bucket, cfg.SrcBucket, cfg.SrcPath)
+
+	_, rtHeaders, err := u.doRESTRequest(ctx, "PUT", cfg.Path, "", headers, nil)
+
+	return rtHeaders, err
+}
+
+// sourcePath builds the fully-qualified "/bucket/path" value expected by
+// X-Upyun-Copy-Source/X-Upyun-Move-Source. srcBucket defaults to bucket
+// when empty, so same-bucket copy/move callers only need to set SrcPath.
+func sourcePath(bucket, srcBucket, srcPath string) string {
+	if srcBucket == "" {
+		srcBucket = bucket
+	}
+	if !strings.HasPrefix(srcPath, "/") {
+		srcPath = "/" + srcPath
+	}
+
+	return "/" + srcBucket + srcPath
+}
+
+// cloneHeaders returns a copy of headers so callers can safely add to it
+// without mutating the caller-supplied map.
+func cloneHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package upyun
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultTimeout is both the default connection timeout and the default
+// read/write timeout, in the absence of an explicit SetConnectionTimeout
+// or SetReadWriteTimeout call.
+const defaultTimeout = 60 * time.Second
+
+// scheme returns "https" or "http" depending on u.Secure.
+func (u *UpYun) scheme() string {
+	if u.Secure {
+		return "https"
+	}
+	return "http"
+}
+
+// SetSecure toggles whether requests are sent over HTTPS (the default,
+// set by NewUpYun) or plaintext HTTP. UPYUN's request signature does not
+// encrypt the payload, so disabling this exposes both the signature and
+// the request/response bodies to anyone on the network path.
+func (u *UpYun) SetSecure(secure bool) {
+	u.Secure = secure
+}
+
+// SetConnectionTimeout bounds how long establishing the TCP connection to
+// the REST API may take.
+func (u *UpYun) SetConnectionTimeout(timeout time.Duration) {
+	u.connectTimeout = timeout
+	u.applyTimeouts()
+}
+
+// SetReadWriteTimeout bounds how long UpYun will wait for response
+// headers once a request has been fully sent, via
+// http.Transport.ResponseHeaderTimeout. It does not bound the time spent
+// writing the request body or reading the response body.
+func (u *UpYun) SetReadWriteTimeout(timeout time.Duration) {
+	u.readWriteTimeout = timeout
+	u.applyTimeouts()
+}
+
+// applyTimeouts updates the connect/read-write timeouts on u's base
+// Transport in place, rather than replacing u.httpClient.Transport
+// outright, so it composes with middleware already installed via Use().
+// If the client's current Transport is itself a *http.Transport (e.g. one
+// SetHTTPClient was just handed, perhaps carrying a custom TLSClientConfig),
+// that transport is adopted as the base instead of being orphaned; any
+// other Transport (nil, or a RoundTripper we can't reach inside) is
+// replaced outright so the timeouts actually take effect.
+func (u *UpYun) applyTimeouts() {
+	if u.httpClient == nil {
+		u.httpClient = &http.Client{}
+	}
+
+	if u.transport == nil {
+		if t, ok := u.httpClient.Transport.(*http.Transport); ok {
+			u.transport = t
+		} else {
+			u.transport = &http.Transport{}
+			u.httpClient.Transport = u.transport
+		}
+	}
+
+	u.transport.DialContext = (&net.Dialer{
+		Timeout: u.connectTimeout,
+	}).DialContext
+	u.transport.ResponseHeaderTimeout = u.readWriteTimeout
+}
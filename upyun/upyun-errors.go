@@ -0,0 +1,66 @@
+package upyun
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Error is a structured UPYUN REST API error, parsed from the JSON error
+// body ({"code":...,"msg":...,"id":...}) UPYUN returns alongside a
+// non-2xx response, so callers can programmatically distinguish e.g. a
+// 404 from a 429 from a 503 instead of string-matching an error message.
+type Error struct {
+	StatusCode int
+	Code       int
+	Msg        string
+	RequestID  string
+	Header     http.Header
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("upyun: %d %s (code %d, request id %s)", e.StatusCode, e.Msg, e.Code, e.RequestID)
+}
+
+// newError builds an *Error from a non-2xx response. When body isn't a
+// valid UPYUN error document (or is empty), Msg falls back to the raw
+// body, or the status text, so no information is lost.
+func newError(statusCode int, header http.Header, body []byte) *Error {
+	e := &Error{StatusCode: statusCode, Header: header}
+
+	var parsed struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		ID   string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		e.Code = parsed.Code
+		e.Msg = parsed.Msg
+		e.RequestID = parsed.ID
+	}
+
+	if e.Msg == "" {
+		if len(body) > 0 {
+			e.Msg = string(body)
+		} else {
+			e.Msg = http.StatusText(statusCode)
+		}
+	}
+
+	return e
+}
+
+// isRetryable reports whether err represents a transient failure worth
+// retrying: a net.Error (timeouts, connection resets, ...), or a
+// structured *Error carrying a 429 or 5xx status.
+func isRetryable(err error) bool {
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	if uerr, ok := err.(*Error); ok {
+		return uerr.StatusCode == http.StatusTooManyRequests || uerr.StatusCode/100 == 5
+	}
+
+	return false
+}
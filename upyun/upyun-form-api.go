@@ -0,0 +1,154 @@
+package upyun
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormUploader issues presigned multipart/form-data uploads against
+// UPYUN's form API (http://v0.api.upyun.com/<bucket>), letting
+// browser/mobile clients upload directly to a bucket without ever being
+// handed the account password.
+type FormUploader struct {
+	Bucket     string
+	FormAPIKey string
+
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewFormUploader returns a new form-API uploader for bucket, authorized
+// with the bucket's form API key, which is distinct from the account
+// password used by UpYun's REST client.
+func NewFormUploader(bucket, formAPIKey string) *FormUploader {
+	return &FormUploader{
+		Bucket:     bucket,
+		FormAPIKey: formAPIKey,
+		endpoint:   "v0.api.upyun.com",
+		httpClient: &http.Client{},
+	}
+}
+
+// FormResp is the JSON body UPYUN returns from a successful form upload.
+type FormResp struct {
+	Code int    `json:"code"`
+	Msg  string `json:"message"`
+	URL  string `json:"url"`
+	Time int64  `json:"time"`
+
+	ImageType   string `json:"image-type,omitempty"`
+	ImageWidth  int    `json:"image-width,omitempty"`
+	ImageHeight int    `json:"image-height,omitempty"`
+	ImageFrames int    `json:"image-frames,omitempty"`
+}
+
+// MakePolicy builds the base64-encoded JSON policy document that must
+// accompany a form upload for saveKey, valid for expireAfter. extra may
+// carry additional policy fields such as notify-url or
+// content-length-range; bucket and save-key are filled in automatically.
+func (f *FormUploader) MakePolicy(saveKey string, expireAfter time.Duration, extra map[string]interface{}) string {
+	policy := make(map[string]interface{}, len(extra)+3)
+	for k, v := range extra {
+		policy[k] = v
+	}
+	policy["bucket"] = f.Bucket
+	policy["save-key"] = saveKey
+	policy["expiration"] = time.Now().Add(expireAfter).Unix()
+
+	data, _ := json.Marshal(policy)
+
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// MakeSignature computes the hex-encoded HMAC-SHA1 signature UPYUN expects
+// over a base64-encoded policy, keyed with the form API key.
+func (f *FormUploader) MakeSignature(encodedPolicy string) string {
+	mac := hmac.New(sha1.New, []byte(f.FormAPIKey))
+	mac.Write([]byte(encodedPolicy))
+
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+// FormUpload uploads the file at localPath to UPYUN through the form
+// API's presigned POST endpoint. policy is used as-is except that bucket
+// and save-key are filled in when absent, the latter defaulting to "/"
+// followed by localPath's base name.
+func (f *FormUploader) FormUpload(localPath string, policy map[string]interface{}) (*FormResp, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if policy == nil {
+		policy = make(map[string]interface{})
+	}
+	if _, ok := policy["bucket"]; !ok {
+		policy["bucket"] = f.Bucket
+	}
+	if _, ok := policy["save-key"]; !ok {
+		policy["save-key"] = "/" + filepath.Base(localPath)
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(data)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("policy", encodedPolicy)
+	writer.WriteField("signature", f.MakeSignature(encodedPolicy))
+
+	part, err := writer.CreateFormFile("file", filepath.Base(localPath))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/%s", f.endpoint, f.Bucket), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.New(string(content))
+	}
+
+	var result FormResp
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,134 @@
+package upyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// errListCapReached is returned internally, up through every recursion
+// level, once cfg.MaxListObjects entries have been emitted. It lets a
+// nested listDir call distinguish "this subdirectory is exhausted" (nil)
+// from "the whole listing must stop now" (this sentinel), so the caller
+// can unwind instead of mistaking the cap for normal completion and
+// emitting more entries or descending into sibling directories.
+var errListCapReached = errors.New("upyun: MaxListObjects reached")
+
+// ListObjects lists the objects under cfg.Path, descending into
+// subdirectories up to cfg.MaxListLevel deep. Unlike GetLargeList, it
+// bounds recursion depth and total object count, retries a failing page
+// fetch instead of treating it as immediately fatal, and stops cleanly
+// when cfg.QuitChan fires or ctx is canceled instead of leaking the
+// listing goroutine.
+func (u *UpYun) ListObjects(ctx context.Context, cfg *GetObjectsConfig) (chan *FileInfo, chan error) {
+	infoChannel := make(chan *FileInfo, 1000)
+	errChannel := make(chan error, 1)
+
+	root := cfg.Path
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	order := "desc"
+	if !cfg.DescOrder {
+		order = "asc"
+	}
+	tries := cfg.MaxListTries
+	if tries <= 0 {
+		tries = 1
+	}
+
+	go func() {
+		defer close(infoChannel)
+		defer close(errChannel)
+
+		// errQuit unwinds every recursion level once QuitChan fires, like
+		// errListCapReached, so a parent doesn't carry on listing.
+		errQuit := errors.New("upyun: listing stopped via QuitChan")
+
+		emitted := 0
+		var listDir func(k string, level int) error
+		listDir = func(k string, level int) error {
+			iter, limit := "", 50
+			for {
+				select {
+				case <-cfg.QuitChan:
+					return errQuit
+				case <-ctx.Done():
+					return ctx.Err()
+				default:
+				}
+
+				infos, niter, err := u.loopListRetry(ctx, k, iter, order, limit, tries)
+				if err != nil {
+					return err
+				}
+				iter = niter
+
+				for _, f := range infos {
+					abs := path.Join(k, f.Name)
+					f.Name = strings.Replace(abs, root, "", 1)
+					if strings.HasPrefix(f.Name, "/") {
+						f.Name = f.Name[1:]
+					}
+
+					if f.Type == "folder" && (cfg.MaxListLevel < 0 || level < cfg.MaxListLevel) {
+						if err := listDir(abs+"/", level+1); err != nil {
+							return err
+						}
+					}
+
+					select {
+					case infoChannel <- f:
+					case <-cfg.QuitChan:
+						return errQuit
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+
+					emitted++
+					if cfg.MaxListObjects > 0 && emitted >= cfg.MaxListObjects {
+						return errListCapReached
+					}
+				}
+
+				if iter == "" {
+					return nil
+				}
+			}
+		}
+
+		if err := listDir(root, 0); err != nil && !errors.Is(err, errListCapReached) && err != errQuit {
+			errChannel <- err
+		}
+	}()
+
+	return infoChannel, errChannel
+}
+
+// loopListRetry fetches a single page via loopList, making up to tries
+// attempts in all and retrying transient errors (net.Error, or a 429/5xx
+// *Error) after a short backoff, and failing fast on any other error, matching
+// uploadMultipartPart's retry semantics.
+func (u *UpYun) loopListRetry(ctx context.Context, key, iter, order string, limit, tries int) ([]*FileInfo, string, error) {
+	var (
+		infos []*FileInfo
+		niter string
+		err   error
+	)
+
+	for i := 0; i < tries; i++ {
+		infos, niter, err = u.loopList(ctx, key, iter, order, limit)
+		if err == nil {
+			return infos, niter, nil
+		}
+		if !isRetryable(err) || i == tries-1 {
+			break
+		}
+		time.Sleep(ResumeWaitTime)
+	}
+
+	return nil, "", fmt.Errorf("upyun: list %s: %w", key, err)
+}
@@ -0,0 +1,280 @@
+package upyun
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPartSize is the smallest part size the multipart subsystem
+	// will pick when auto-sizing parts for a file.
+	DefaultPartSize int64 = 1 * 1024 * 1024
+
+	// MaxPartNum is the maximum number of parts a single multipart
+	// upload may be split into, per the UPYUN REST API.
+	MaxPartNum = 10000
+)
+
+// multipartState is the sidecar persisted next to an in-progress multipart
+// upload so it can be resumed across process restarts by re-reading it and
+// skipping the parts already marked Done.
+type multipartState struct {
+	UUID      string `json:"uuid"`
+	PartSize  int64  `json:"part_size"`
+	MaxPartID int    `json:"max_part_id"`
+	Done      []bool `json:"done"`
+}
+
+func multipartStatePath(f *os.File) string {
+	return f.Name() + ".upyun-multipart.json"
+}
+
+func loadMultipartState(path string) *multipartState {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var state multipartState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+
+	return &state
+}
+
+func (s *multipartState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// effectivePartSize starts from requested (raised to DefaultPartSize if
+// smaller) and doubles it until the number of parts for a file of the
+// given size is within MaxPartNum. The result is not necessarily the
+// smallest part size that fits.
+func effectivePartSize(fileSize, requested int64) int64 {
+	partSize := requested
+	if partSize < DefaultPartSize {
+		partSize = DefaultPartSize
+	}
+
+	for ceilDiv(fileSize, partSize) > MaxPartNum {
+		partSize *= 2
+	}
+
+	return partSize
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+// multipartPut uploads value part by part via cfg's resume-upload knobs,
+// running cfg.Parallel workers concurrently and persisting progress to a
+// sidecar JSON file so an interrupted upload can be resumed by retrying
+// the same PutObjectConfig against the same LocalPath.
+func (u *UpYun) multipartPut(ctx context.Context, cfg *PutObjectConfig, value *os.File, reporter ResumeReporter) (http.Header, error) {
+	fileInfo, err := value.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	statePath := multipartStatePath(value)
+	partSize := effectivePartSize(fileInfo.Size(), cfg.ResumePartSize)
+	maxPartID := int(ceilDiv(fileInfo.Size(), partSize)) - 1
+
+	state := loadMultipartState(statePath)
+	if state == nil || state.PartSize != partSize || state.MaxPartID != maxPartID {
+		state = &multipartState{PartSize: partSize, MaxPartID: maxPartID, Done: make([]bool, maxPartID+1)}
+	}
+
+	if state.UUID == "" {
+		uuid, err := u.initiateMultipart(ctx, cfg, fileInfo.Size())
+		if err != nil {
+			return nil, err
+		}
+		state.UUID = uuid
+		if err := state.save(statePath); err != nil {
+			return nil, err
+		}
+	}
+
+	tries := cfg.MaxResumePutTries
+	if tries <= 0 {
+		tries = ResumeRetryCount + 1
+	}
+	parallel := cfg.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	partCh := make(chan int)
+	go func() {
+		defer close(partCh)
+		for part := 0; part <= maxPartID; part++ {
+			if state.Done[part] {
+				continue
+			}
+			select {
+			case partCh <- part:
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range partCh {
+				if err := u.uploadMultipartPart(runCtx, cfg, value, state, part, tries); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+					return
+				}
+
+				mu.Lock()
+				state.Done[part] = true
+				saveErr := state.save(statePath)
+				mu.Unlock()
+				if saveErr != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = saveErr
+						cancel()
+					}
+					mu.Unlock()
+					return
+				}
+				if reporter != nil {
+					reporter(part, maxPartID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	rtHeaders, err := u.completeMultipart(ctx, cfg, value, state.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	os.Remove(statePath)
+
+	return rtHeaders, nil
+}
+
+// initiateMultipart issues the zero-byte "initiate" stage request and
+// returns the X-Upyun-Multi-Uuid UPYUN assigns the upload.
+func (u *UpYun) initiateMultipart(ctx context.Context, cfg *PutObjectConfig, fileSize int64) (string, error) {
+	headers := cloneHeaders(cfg.Headers)
+	headers["X-Upyun-Multi-Stage"] = "initiate"
+	headers["X-Upyun-Multi-Type"] = cfg.Headers["Content-Type"]
+	headers["X-Upyun-Multi-Length"] = strconv.FormatInt(fileSize, 10)
+	headers["Content-Length"] = "0"
+
+	_, rtHeaders, err := u.doRESTRequest(ctx, "PUT", cfg.Path, "", headers, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return rtHeaders.Get("X-Upyun-Multi-Uuid"), nil
+}
+
+// uploadMultipartPart uploads a single part, retrying transient failures
+// (net.Error, or a 429/5xx *Error) with backoff and failing fast on any
+// other error (e.g. a 4xx response), matching ResumePut's original
+// per-upload retry semantics.
+func (u *UpYun) uploadMultipartPart(ctx context.Context, cfg *PutObjectConfig, value *os.File,
+	state *multipartState, part, tries int) error {
+	offset := int64(part) * state.PartSize
+	size := state.PartSize
+	if part == state.MaxPartID {
+		if fileInfo, err := value.Stat(); err == nil {
+			size = fileInfo.Size() - offset
+		}
+	}
+
+	var err error
+	for i := 0; i < tries; i++ {
+		var file *FragmentFile
+		file, err = NewFragmentFile(value, offset, size)
+		if err != nil {
+			return err
+		}
+
+		headers := cloneHeaders(cfg.Headers)
+		headers["X-Upyun-Multi-Stage"] = "upload"
+		headers["X-Upyun-Multi-UUID"] = state.UUID
+		headers["X-Upyun-Part-Id"] = strconv.Itoa(part)
+		headers["Content-Length"] = strconv.FormatInt(size, 10)
+		if cfg.UseMD5 {
+			headers["Content-MD5"], _ = file.MD5()
+			file.Seek(0, 0)
+		}
+
+		_, _, err = u.doRESTRequest(ctx, "PUT", cfg.Path, "", headers, file)
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+
+		time.Sleep(ResumeWaitTime)
+	}
+
+	return err
+}
+
+// completeMultipart issues the "complete" stage request, finalizing the
+// multipart upload. The aggregate MD5 of the whole file is sent with it
+// only when cfg.UseMD5 is set.
+func (u *UpYun) completeMultipart(ctx context.Context, cfg *PutObjectConfig, value *os.File, uuid string) (http.Header, error) {
+	headers := cloneHeaders(cfg.Headers)
+	headers["X-Upyun-Multi-Stage"] = "complete"
+	headers["X-Upyun-Multi-UUID"] = uuid
+	headers["Content-Length"] = "0"
+
+	if cfg.UseMD5 {
+		if _, err := value.Seek(0, 0); err != nil {
+			return nil, err
+		}
+		hex, _, err := md5sum(value)
+		if err != nil {
+			return nil, err
+		}
+		headers["X-Upyun-Multi-MD5"] = hex
+	}
+
+	_, rtHeaders, err := u.doRESTRequest(ctx, "PUT", cfg.Path, "", headers, nil)
+
+	return rtHeaders, err
+}
@@ -0,0 +1,94 @@
+package upyun
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCeilDiv(t *testing.T) {
+	cases := []struct {
+		a, b, want int64
+	}{
+		{0, 10, 0},
+		{1, 10, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{20, 10, 2},
+	}
+
+	for _, c := range cases {
+		if got := ceilDiv(c.a, c.b); got != c.want {
+			t.Errorf("ceilDiv(%d, %d) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestEffectivePartSize(t *testing.T) {
+	cases := []struct {
+		name      string
+		fileSize  int64
+		requested int64
+	}{
+		{"small file, no request", 1024, 0},
+		{"small file, small request", 1024, 1},
+		{"huge file needs bigger parts than requested", MaxPartNum*DefaultPartSize + 1, DefaultPartSize},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			partSize := effectivePartSize(c.fileSize, c.requested)
+
+			if partSize < DefaultPartSize {
+				t.Fatalf("effectivePartSize(%d, %d) = %d, want >= DefaultPartSize (%d)",
+					c.fileSize, c.requested, partSize, DefaultPartSize)
+			}
+			if ceilDiv(c.fileSize, partSize) > MaxPartNum {
+				t.Fatalf("effectivePartSize(%d, %d) = %d, yields %d parts, want <= MaxPartNum (%d)",
+					c.fileSize, c.requested, partSize, ceilDiv(c.fileSize, partSize), MaxPartNum)
+			}
+		})
+	}
+}
+
+func TestMultipartStateSaveLoadRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upyun-multipart-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/state.json"
+	want := &multipartState{
+		UUID:      "test-uuid",
+		PartSize:  DefaultPartSize,
+		MaxPartID: 3,
+		Done:      []bool{true, true, false, false},
+	}
+
+	if err := want.save(path); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+
+	got := loadMultipartState(path)
+	if got == nil {
+		t.Fatal("loadMultipartState() = nil, want non-nil")
+	}
+	if got.UUID != want.UUID || got.PartSize != want.PartSize || got.MaxPartID != want.MaxPartID {
+		t.Fatalf("loadMultipartState() = %+v, want %+v", got, want)
+	}
+	if len(got.Done) != len(want.Done) {
+		t.Fatalf("loadMultipartState().Done = %v, want %v", got.Done, want.Done)
+	}
+	for i := range want.Done {
+		if got.Done[i] != want.Done[i] {
+			t.Fatalf("loadMultipartState().Done[%d] = %v, want %v", i, got.Done[i], want.Done[i])
+		}
+	}
+}
+
+func TestLoadMultipartStateMissingFile(t *testing.T) {
+	if state := loadMultipartState("/nonexistent/upyun-multipart-state.json"); state != nil {
+		t.Fatalf("loadMultipartState() = %+v, want nil for a missing sidecar", state)
+	}
+}
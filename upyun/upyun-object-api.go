@@ -0,0 +1,91 @@
+package upyun
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+)
+
+// PutObject uploads an object to UPYUN File System. Unlike Put, it takes a
+// context.Context so the caller can cancel or time out a long-running
+// upload, and a PutObjectConfig describing the source and per-operation
+// options instead of a fixed argument list.
+func (u *UpYun) PutObject(ctx context.Context, cfg *PutObjectConfig) (http.Header, error) {
+	if cfg.UseResumeUpload {
+		f, owned, err := cfg.localFile()
+		if err != nil {
+			return nil, err
+		}
+		if owned {
+			defer f.Close()
+		}
+
+		return u.multipartPut(ctx, cfg, f, nil)
+	}
+
+	value := cfg.Reader
+	if cfg.LocalPath != "" {
+		f, err := os.Open(cfg.LocalPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		value = f
+	}
+	if value == nil {
+		return nil, errors.New("upyun: PutObjectConfig needs either LocalPath or Reader")
+	}
+
+	return u.putObject(ctx, cfg.Path, value, cfg.UseMD5, cfg.Headers)
+}
+
+// localFile resolves the config's upload source to an *os.File, since the
+// multipart subsystem needs to seek independent FragmentFile views of it.
+// owned reports whether the caller must close the returned file.
+func (cfg *PutObjectConfig) localFile() (f *os.File, owned bool, err error) {
+	if cfg.LocalPath != "" {
+		f, err = os.Open(cfg.LocalPath)
+		return f, true, err
+	}
+	if f, ok := cfg.Reader.(*os.File); ok {
+		return f, false, nil
+	}
+
+	return nil, false, errors.New("upyun: UseResumeUpload requires LocalPath or a *os.File Reader")
+}
+
+// GetObject downloads an object from UPYUN File System. Unlike Get, it
+// takes a context.Context so the caller can cancel or time out a
+// long-running download, and a GetObjectConfig describing the destination.
+func (u *UpYun) GetObject(ctx context.Context, cfg *GetObjectConfig) (int, error) {
+	value := cfg.Writer
+	if cfg.LocalPath != "" {
+		f, err := os.Create(cfg.LocalPath)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		value = f
+	}
+	if value == nil {
+		return 0, errors.New("upyun: GetObjectConfig needs either LocalPath or Writer")
+	}
+
+	return u.getObject(ctx, cfg.Path, value)
+}
+
+// DeleteObject deletes the specified **file** in UPYUN File System. Unlike
+// Delete, it takes a context.Context and a DeleteObjectConfig so the
+// caller can request an asynchronous delete or pass custom headers.
+func (u *UpYun) DeleteObject(ctx context.Context, cfg *DeleteObjectConfig) error {
+	headers := cfg.Headers
+	if cfg.Async {
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers["X-Upyun-Async"] = "true"
+	}
+
+	return u.deleteObject(ctx, cfg.Path, headers)
+}
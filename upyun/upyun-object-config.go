@@ -0,0 +1,93 @@
+package upyun
+
+import "io"
+
+// PutObjectConfig describes the options accepted by (*UpYun).PutObject.
+//
+// Exactly one of LocalPath or Reader should be set as the source of the
+// upload. When UseResumeUpload is set, the object is uploaded part by
+// part via the multipart subsystem instead of a single PUT.
+type PutObjectConfig struct {
+	Path    string
+	Headers map[string]string
+
+	LocalPath string
+	Reader    io.Reader
+
+	UseMD5 bool
+
+	UseResumeUpload   bool
+	ResumePartSize    int64
+	MaxResumePutTries int
+	Parallel          int
+}
+
+// GetObjectConfig describes the options accepted by (*UpYun).GetObject.
+//
+// Exactly one of LocalPath or Writer should be set as the destination of
+// the download.
+type GetObjectConfig struct {
+	Path    string
+	Headers map[string]string
+
+	LocalPath string
+	Writer    io.Writer
+}
+
+// GetObjectsConfig describes the options accepted by (*UpYun).ListObjects.
+// Path must name a directory.
+type GetObjectsConfig struct {
+	Path string
+
+	// MaxListObjects stops the listing after this many entries have been
+	// emitted. Zero means no limit.
+	MaxListObjects int
+
+	// MaxListLevel bounds how many directory levels ListObjects will
+	// recurse into below Path. Zero means Path itself only (no
+	// recursion); a negative value means unbounded recursion.
+	MaxListLevel int
+
+	// MaxListTries bounds how many attempts ListObjects makes at a single
+	// page fetch before it gives up on a transient error and reports it.
+	// Zero defaults to 1 (no retry).
+	MaxListTries int
+
+	DescOrder bool
+
+	// QuitChan, when non-nil, lets the caller stop an in-flight listing
+	// early: closing it (or sending on it) makes ListObjects stop
+	// fetching further pages and close its output channels.
+	QuitChan chan struct{}
+}
+
+// DeleteObjectConfig describes the options accepted by (*UpYun).DeleteObject.
+type DeleteObjectConfig struct {
+	Path    string
+	Headers map[string]string
+	Async   bool
+}
+
+// CopyObjectConfig describes the options accepted by (*UpYun).Copy. SrcPath
+// is resolved against SrcBucket, which defaults to the client's own Bucket
+// when empty, so objects can be copied from another bucket the same
+// account has access to.
+type CopyObjectConfig struct {
+	Path    string
+	Headers map[string]string
+
+	SrcPath   string
+	SrcBucket string
+}
+
+// MoveObjectConfig describes the options accepted by (*UpYun).Move. SrcPath
+// is resolved against SrcBucket, which defaults to the client's own Bucket
+// when empty, so objects can be moved from another bucket the same
+// account has access to.
+type MoveObjectConfig struct {
+	Path    string
+	Headers map[string]string
+
+	SrcPath   string
+	SrcBucket string
+}
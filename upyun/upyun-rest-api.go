@@ -2,17 +2,16 @@ package upyun
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	URL "net/url"
 	"os"
-	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -27,26 +26,47 @@ type UpYun struct {
 	Username  string
 	Passwd    string
 	ChunkSize int
+
+	// Secure selects whether requests are sent over HTTPS (the
+	// default) or plaintext HTTP. See SetSecure.
+	Secure bool
+
+	connectTimeout   time.Duration
+	readWriteTimeout time.Duration
+	transport        *http.Transport
 }
 
 // NewUpYun return a new UPYUN REST API client given a bucket name,
-// username, password. As Default, endpoint is set to Auto, http
-// client connection timeout is set to defalutConnectionTimeout which
-// is equal to 60 seconds.
+// username, password. As Default, endpoint is set to Auto, requests are
+// sent over HTTPS, and both the connection timeout and the read/write
+// timeout are set to defaultTimeout, which is equal to 60 seconds.
 func NewUpYun(bucket, username, passwd string) *UpYun {
 	u := &UpYun{
 		Bucket:   bucket,
 		Username: username,
 		Passwd:   passwd,
+		Secure:   true,
 	}
 
 	u.httpClient = &http.Client{}
 	u.SetEndpoint(Auto)
-	u.SetTimeout(defaultConnectTimeout)
+	u.SetConnectionTimeout(defaultTimeout)
+	u.SetReadWriteTimeout(defaultTimeout)
 
 	return u
 }
 
+// SetHTTPClient replaces the *http.Client used to issue every request, so
+// callers can inject a custom Transport for tracing, metrics, connection
+// pooling tuned for many small PUTs, or a custom TLS config. It discards
+// the base Transport SetConnectionTimeout/SetReadWriteTimeout manage, so
+// a subsequent call to either rewires client's Transport instead of
+// silently mutating the orphaned one from before.
+func (u *UpYun) SetHTTPClient(client *http.Client) {
+	u.httpClient = client
+	u.transport = nil
+}
+
 // SetEndpoint sets the request endpoint to UPYUN REST API Server.
 func (u *UpYun) SetEndpoint(ed int) error {
 	if ed >= Auto && ed <= Ctt {
@@ -79,7 +99,7 @@ func (u *UpYun) makePurgeAuth(purgeList, date string) string {
 
 // Usage gets the usage of the bucket in UPYUN File System
 func (u *UpYun) Usage() (int64, error) {
-	result, _, err := u.doRESTRequest("GET", "/", "usage", nil, nil)
+	result, _, err := u.doRESTRequest(context.Background(), "GET", "/", "usage", nil, nil)
 	if err != nil {
 		return 0, err
 	}
@@ -94,13 +114,19 @@ func (u *UpYun) Mkdir(key string) error {
 	headers["mkdir"] = "true"
 	headers["folder"] = "true"
 
-	_, _, err := u.doRESTRequest("POST", key, "", headers, nil)
+	_, _, err := u.doRESTRequest(context.Background(), "POST", key, "", headers, nil)
 
 	return err
 }
 
 // Put uploads filelike object to UPYUN File System
 func (u *UpYun) Put(key string, value io.Reader, useMD5 bool,
+	headers map[string]string) (http.Header, error) {
+	return u.putObject(context.Background(), key, value, useMD5, headers)
+}
+
+// putObject is the context-aware implementation shared by Put and PutObject.
+func (u *UpYun) putObject(ctx context.Context, key string, value io.Reader, useMD5 bool,
 	headers map[string]string) (http.Header, error) {
 	if headers == nil {
 		headers = make(map[string]string)
@@ -142,19 +168,18 @@ func (u *UpYun) Put(key string, value io.Reader, useMD5 bool,
 		}
 	}
 
-	_, rtHeaders, err := u.doRESTRequest("PUT", key, "", headers, value)
+	_, rtHeaders, err := u.doRESTRequest(ctx, "PUT", key, "", headers, value)
 
 	return rtHeaders, err
 }
 
-// Put uploads file object to UPYUN File System part by part,
-// and automatically retries when a network problem occurs
+// ResumePut uploads file object to UPYUN File System part by part,
+// and automatically retries when a network problem occurs. It is a thin
+// wrapper around the multipart subsystem behind PutObjectConfig's
+// UseResumeUpload option; new callers that need parallelism or
+// cross-restart resume should use PutObject directly.
 func (u *UpYun) ResumePut(key string, value *os.File, useMD5 bool,
 	headers map[string]string, reporter ResumeReporter) (http.Header, error) {
-	if headers == nil {
-		headers = make(map[string]string)
-	}
-
 	fileinfo, err := value.Stat()
 	if err != nil {
 		return nil, err
@@ -165,80 +190,27 @@ func (u *UpYun) ResumePut(key string, value *os.File, useMD5 bool,
 		return u.Put(key, value, useMD5, headers)
 	}
 
-	maxPartID := int(fileinfo.Size() / resumePartSize)
-	if fileinfo.Size()%resumePartSize == 0 {
-		maxPartID--
+	cfg := &PutObjectConfig{
+		Path:              key,
+		Headers:           headers,
+		Reader:            value,
+		UseMD5:            useMD5,
+		UseResumeUpload:   true,
+		ResumePartSize:    resumePartSize,
+		MaxResumePutTries: ResumeRetryCount + 1,
 	}
 
-	var resp http.Header
-
-	for part := 0; part <= maxPartID; part++ {
-
-		innerHeaders := make(map[string]string)
-		for k, v := range headers {
-			innerHeaders[k] = v
-		}
-
-		innerHeaders["X-Upyun-Part-Id"] = strconv.Itoa(part)
-		switch part {
-		case 0:
-			innerHeaders["X-Upyun-Multi-Type"] = headers["Content-Type"]
-			innerHeaders["X-Upyun-Multi-Length"] = strconv.FormatInt(fileinfo.Size(), 10)
-			innerHeaders["X-Upyun-Multi-Stage"] = "initiate,upload"
-			innerHeaders["Content-Length"] = strconv.Itoa(resumePartSize)
-		case maxPartID:
-			innerHeaders["X-Upyun-Multi-Stage"] = "upload,complete"
-			innerHeaders["Content-Length"] = fmt.Sprint(fileinfo.Size() - int64(resumePartSize)*int64(part))
-			if useMD5 {
-				value.Seek(0, 0)
-				hex, _, _ := md5sum(value)
-				innerHeaders["X-Upyun-Multi-MD5"] = hex
-			}
-		default:
-			innerHeaders["X-Upyun-Multi-Stage"] = "upload"
-			innerHeaders["Content-Length"] = strconv.Itoa(resumePartSize)
-		}
-
-		file, err := NewFragmentFile(value, int64(part)*int64(resumePartSize), resumePartSize)
-		if err != nil {
-			return resp, err
-		}
-		if useMD5 {
-			innerHeaders["Content-MD5"], _ = file.MD5()
-		}
-
-		// Retry when get net error from UpYun.Put(), return error in other cases
-		for i := 0; i < ResumeRetryCount+1; i++ {
-			resp, err = u.Put(key, file, useMD5, innerHeaders)
-			if err == nil {
-				break
-			}
-			// Retry only get net error
-			_, ok := err.(net.Error)
-			if !ok {
-				return resp, err
-			}
-			if i == ResumeRetryCount {
-				return resp, err
-			}
-			time.Sleep(ResumeWaitTime)
-			file.Seek(0, 0)
-		}
-		if reporter != nil {
-			reporter(part, maxPartID)
-		}
-
-		if part == 0 {
-			headers["X-Upyun-Multi-UUID"] = resp.Get("X-Upyun-Multi-Uuid")
-		}
-	}
-
-	return resp, nil
+	return u.multipartPut(context.Background(), cfg, value, reporter)
 }
 
 // Get gets the specified file in UPYUN File System
 func (u *UpYun) Get(key string, value io.Writer) (int, error) {
-	length, _, err := u.doRESTRequest("GET", key, "", nil, value)
+	return u.getObject(context.Background(), key, value)
+}
+
+// getObject is the context-aware implementation shared by Get and GetObject.
+func (u *UpYun) getObject(ctx context.Context, key string, value io.Writer) (int, error) {
+	length, _, err := u.doRESTRequest(ctx, "GET", key, "", nil, value)
 	if err != nil {
 		return 0, err
 	}
@@ -247,9 +219,7 @@ func (u *UpYun) Get(key string, value io.Writer) (int, error) {
 
 // Delete deletes the specified **file** in UPYUN File System.
 func (u *UpYun) Delete(key string) error {
-	_, _, err := u.doRESTRequest("DELETE", key, "", nil, nil)
-
-	return err
+	return u.deleteObject(context.Background(), key, nil)
 }
 
 // AsyncDelete deletes the specified **file** in UPYUN File System asynchronously.
@@ -257,7 +227,13 @@ func (u *UpYun) AsyncDelete(key string) error {
 	headers := map[string]string{
 		"X-Upyun-Async": "true",
 	}
-	_, _, err := u.doRESTRequest("DELETE", key, "", headers, nil)
+	return u.deleteObject(context.Background(), key, headers)
+}
+
+// deleteObject is the context-aware implementation shared by Delete,
+// AsyncDelete and DeleteObject.
+func (u *UpYun) deleteObject(ctx context.Context, key string, headers map[string]string) error {
+	_, _, err := u.doRESTRequest(ctx, "DELETE", key, "", headers, nil)
 
 	return err
 }
@@ -265,7 +241,7 @@ func (u *UpYun) AsyncDelete(key string) error {
 // GetList lists items in key. The number of items must be
 // less then 100
 func (u *UpYun) GetList(key string) ([]*FileInfo, error) {
-	ret, _, err := u.doRESTRequest("GET", key, "", nil, nil)
+	ret, _, err := u.doRESTRequest(context.Background(), "GET", key, "", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -283,65 +259,25 @@ func (u *UpYun) GetList(key string) ([]*FileInfo, error) {
 	return infoList, nil
 }
 
-// Note: key must be directory
+// GetLargeList lists items in key, descending into subdirectories when
+// recursive is set. It is a thin wrapper around ListObjects with an
+// unbounded MaxListLevel and no MaxListObjects cap, kept for backwards
+// compatibility.
 func (u *UpYun) GetLargeList(key string, asc, recursive bool) (chan *FileInfo, chan error) {
-	infoChannel := make(chan *FileInfo, 1000)
-	errChannel := make(chan error, 10)
-	if !strings.HasSuffix(key, "/") {
-		key += "/"
-	}
-	order := "desc"
-	if asc == true {
-		order = "asc"
-	}
-
-	go func() {
-		var listDir func(k string) error
-		listDir = func(k string) error {
-			var infos []*FileInfo
-			var niter string
-			var err error
-			iter, limit := "", 50
-			for {
-				infos, niter, err = u.loopList(k, iter, order, limit)
-				if err != nil {
-					errChannel <- err
-					return err
-				}
-				iter = niter
-				for _, f := range infos {
-					// absolute path
-					abs := path.Join(k, f.Name)
-					// relative path
-					f.Name = strings.Replace(abs, key, "", 1)
-					if f.Name[0] == '/' {
-						f.Name = f.Name[1:]
-					}
-					if recursive && f.Type == "folder" {
-						if err = listDir(abs + "/"); err != nil {
-							return err
-						}
-					}
-					infoChannel <- f
-				}
-				if iter == "" {
-					break
-				}
-			}
-			return nil
-		}
-
-		listDir(key)
-
-		close(errChannel)
-		close(infoChannel)
-	}()
+	maxLevel := 0
+	if recursive {
+		maxLevel = -1
+	}
 
-	return infoChannel, errChannel
+	return u.ListObjects(context.Background(), &GetObjectsConfig{
+		Path:         key,
+		MaxListLevel: maxLevel,
+		DescOrder:    !asc,
+	})
 }
 
-// LoopList list items iteratively.
-func (u *UpYun) loopList(key, iter, order string, limit int) ([]*FileInfo, string, error) {
+// loopList fetches a single page of key's listing.
+func (u *UpYun) loopList(ctx context.Context, key, iter, order string, limit int) ([]*FileInfo, string, error) {
 	headers := map[string]string{
 		"X-List-Limit": fmt.Sprint(limit),
 		"X-List-Order": order,
@@ -350,7 +286,7 @@ func (u *UpYun) loopList(key, iter, order string, limit int) ([]*FileInfo, strin
 		headers["X-List-Iter"] = iter
 	}
 
-	ret, rtHeaders, err := u.doRESTRequest("GET", key, "", headers, nil)
+	ret, rtHeaders, err := u.doRESTRequest(ctx, "GET", key, "", headers, nil)
 	if err != nil {
 		return nil, "", err
 	}
@@ -364,13 +300,11 @@ func (u *UpYun) loopList(key, iter, order string, limit int) ([]*FileInfo, strin
 		infoList = append(infoList, newFileInfo(v))
 	}
 
-	nextIter := ""
-	if _, ok := rtHeaders["X-Upyun-List-Iter"]; ok {
-		nextIter = rtHeaders["X-Upyun-List-Iter"][0]
-	} else {
-		// Maybe Wrong
-		return nil, "", nil
+	iterValues, ok := rtHeaders["X-Upyun-List-Iter"]
+	if !ok {
+		return nil, "", errors.New("upyun: response is missing X-Upyun-List-Iter header")
 	}
+	nextIter := iterValues[0]
 
 	if nextIter == "g2gCZAAEbmV4dGQAA2VvZg" {
 		nextIter = ""
@@ -381,7 +315,7 @@ func (u *UpYun) loopList(key, iter, order string, limit int) ([]*FileInfo, strin
 
 // GetInfo gets information of item in UPYUN File System
 func (u *UpYun) GetInfo(key string) (*FileInfo, error) {
-	_, headers, err := u.doRESTRequest("HEAD", key, "", nil, nil)
+	_, headers, err := u.doRESTRequest(context.Background(), "HEAD", key, "", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -393,7 +327,7 @@ func (u *UpYun) GetInfo(key string) (*FileInfo, error) {
 
 // Purge post a purge request to UPYUN Purge Server
 func (u *UpYun) Purge(urls []string) (string, error) {
-	purge := "http://purge.upyun.com/purge/"
+	purge := u.scheme() + "://purge.upyun.com/purge/"
 
 	date := genRFC1123Date()
 	purgeList := strings.Join(urls, "\n")
@@ -407,7 +341,10 @@ func (u *UpYun) Purge(urls []string) (string, error) {
 	form.Add("purge", purgeList)
 
 	body := strings.NewReader(form.Encode())
-	resp, err := u.doHTTPRequest("POST", purge, headers, body)
+	resp, err := u.doHTTPRequest(context.Background(), "POST", purge, headers, body)
+	if err != nil {
+		return "", err
+	}
 	defer resp.Body.Close()
 
 	content, err := ioutil.ReadAll(resp.Body)
@@ -428,7 +365,7 @@ func (u *UpYun) Purge(urls []string) (string, error) {
 	return "", errors.New(string(content))
 }
 
-func (u *UpYun) doRESTRequest(method, uri, query string, headers map[string]string,
+func (u *UpYun) doRESTRequest(ctx context.Context, method, uri, query string, headers map[string]string,
 	value interface{}) (result string, rtHeaders http.Header, err error) {
 	if headers == nil {
 		headers = make(map[string]string)
@@ -440,7 +377,7 @@ func (u *UpYun) doRESTRequest(method, uri, query string, headers map[string]stri
 	}
 
 	uri = escapeURI("/" + u.Bucket + uri)
-	url := fmt.Sprintf("http://%s%s", u.endpoint, uri)
+	url := fmt.Sprintf("%s://%s%s", u.scheme(), u.endpoint, uri)
 
 	if query != "" {
 		query = escapeURI(query)
@@ -468,7 +405,7 @@ func (u *UpYun) doRESTRequest(method, uri, query string, headers map[string]stri
 		rc = nil
 	}
 
-	resp, err := u.doHTTPRequest(method, url, headers, rc)
+	resp, err := u.doHTTPRequest(ctx, method, url, headers, rc)
 	if err != nil {
 		return "", nil, err
 	}
@@ -484,12 +421,10 @@ func (u *UpYun) doRESTRequest(method, uri, query string, headers map[string]stri
 		return string(body), resp.Header, err
 	}
 
-	if body, err := ioutil.ReadAll(resp.Body); err == nil {
-		if len(body) == 0 && resp.StatusCode/100 != 2 {
-			return "", resp.Header, errors.New(fmt.Sprint(resp.StatusCode))
-		}
-		return "", resp.Header, errors.New(string(body))
-	} else {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
 		return "", resp.Header, err
 	}
+
+	return "", resp.Header, newError(resp.StatusCode, resp.Header, body)
 }
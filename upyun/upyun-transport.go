@@ -0,0 +1,35 @@
+package upyun
+
+import "net/http"
+
+// RoundTripperFunc adapts a function to an http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps an http.RoundTripper with additional behavior, such as
+// tracing, metrics, or logging.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// Use installs middleware around the client's current Transport, in the
+// order passed: the first middleware sees a request first and its
+// response last. It replaces a nil Transport with http.DefaultTransport
+// before wrapping.
+func (u *UpYun) Use(middleware ...Middleware) {
+	if u.httpClient == nil {
+		u.httpClient = &http.Client{}
+	}
+
+	transport := u.httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		transport = middleware[i](transport)
+	}
+
+	u.httpClient.Transport = transport
+}